@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// checkpoint records progress through a paginated CDX crawl so it can be
+// resumed after an interruption. It is persisted as a sidecar JSON file
+// next to the output file.
+type checkpoint struct {
+	ResumeKey string    `json:"resumeKey"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// checkpointPath returns the sidecar state file path for a given output file.
+func checkpointPath(outputFile string) string {
+	return outputFile + ".state.json"
+}
+
+// loadCheckpoint reads a checkpoint from path. It returns a nil checkpoint
+// (and no error) if the file does not exist.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint writes cp to path, overwriting any existing state.
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// clearCheckpoint removes the state file once a crawl completes successfully.
+func clearCheckpoint(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json.state.json")
+
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint on missing file: unexpected error: %v", err)
+	}
+	if cp != nil {
+		t.Fatalf("loadCheckpoint on missing file: expected nil checkpoint, got %+v", cp)
+	}
+
+	want := checkpoint{ResumeKey: "abc123", UpdatedAt: time.Now().Truncate(time.Second)}
+	if err := saveCheckpoint(path, want); err != nil {
+		t.Fatalf("saveCheckpoint: unexpected error: %v", err)
+	}
+
+	got, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatal("loadCheckpoint: expected non-nil checkpoint")
+	}
+	if got.ResumeKey != want.ResumeKey {
+		t.Errorf("ResumeKey = %q, want %q", got.ResumeKey, want.ResumeKey)
+	}
+	if !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, want.UpdatedAt)
+	}
+
+	if err := clearCheckpoint(path); err != nil {
+		t.Fatalf("clearCheckpoint: unexpected error: %v", err)
+	}
+
+	cp, err = loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint after clear: unexpected error: %v", err)
+	}
+	if cp != nil {
+		t.Fatalf("loadCheckpoint after clear: expected nil checkpoint, got %+v", cp)
+	}
+
+	// Clearing an already-cleared checkpoint is not an error.
+	if err := clearCheckpoint(path); err != nil {
+		t.Fatalf("clearCheckpoint on missing file: unexpected error: %v", err)
+	}
+}
+
+func TestLoadCheckpointCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json.state.json")
+	if err := os.WriteFile(path, []byte("not valid json"), 0644); err != nil {
+		t.Fatalf("failed to seed corrupt checkpoint: %v", err)
+	}
+
+	if _, err := loadCheckpoint(path); err == nil {
+		t.Fatal("loadCheckpoint on corrupt file: expected error, got nil")
+	}
+}
+
+func TestCheckpointPath(t *testing.T) {
+	if got, want := checkpointPath("out.json"), "out.json.state.json"; got != want {
+		t.Errorf("checkpointPath(%q) = %q, want %q", "out.json", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+// Package fetcher downloads archived response bodies referenced by CDX
+// records and lays them out on disk in a WARC-like directory structure,
+// deduplicating identical payloads by their CDX digest.
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is the subset of main's RateLimiter the fetcher needs, so the
+// same limiter instance used for CDX requests can be shared here.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Record is the subset of a CDX row required to fetch and dedup a snapshot.
+type Record struct {
+	URL       string
+	Timestamp string
+	MimeType  string
+	Digest    string
+}
+
+// ManifestEntry is one JSON line written to the fetch manifest, mapping a
+// fetched (url, timestamp) pair to where it landed on disk.
+type ManifestEntry struct {
+	URL       string `json:"url"`
+	Timestamp string `json:"timestamp"`
+	Digest    string `json:"digest"`
+	Path      string `json:"path,omitempty"`
+	Bytes     int    `json:"bytes"`
+	Deduped   bool   `json:"deduped"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Fetcher downloads archived snapshots with a bounded worker pool, skipping
+// any digest it has already downloaded.
+type Fetcher struct {
+	OutDir      string
+	Concurrent  int
+	Client      *http.Client
+	RateLimiter RateLimiter
+
+	mu           sync.Mutex
+	seen         map[string]string // digest -> local path
+	manifest     *json.Encoder
+	manifestFile *os.File
+}
+
+// New creates a Fetcher that writes snapshots under outDir and appends
+// manifest entries to outDir/manifest.jsonl.
+func New(outDir string, concurrent int, rl RateLimiter) (*Fetcher, error) {
+	if concurrent <= 0 {
+		concurrent = 1
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	manifestFile, err := os.OpenFile(filepath.Join(outDir, "manifest.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %v", err)
+	}
+
+	return &Fetcher{
+		OutDir:       outDir,
+		Concurrent:   concurrent,
+		Client:       &http.Client{Timeout: 60 * time.Second},
+		RateLimiter:  rl,
+		seen:         make(map[string]string),
+		manifest:     json.NewEncoder(manifestFile),
+		manifestFile: manifestFile,
+	}, nil
+}
+
+// Close releases the manifest file handle.
+func (f *Fetcher) Close() error {
+	return f.manifestFile.Close()
+}
+
+// Fetch downloads every record in records, using up to f.Concurrent workers.
+// Records sharing a digest with one already downloaded are recorded in the
+// manifest as deduped rather than fetched again. Fetch returns ctx.Err() if
+// ctx is canceled before all records finish; records already in flight are
+// still written to the manifest.
+func (f *Fetcher) Fetch(ctx context.Context, records []Record) error {
+	jobs := make(chan Record)
+	var wg sync.WaitGroup
+
+	for i := 0; i < f.Concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rec := range jobs {
+				f.fetchOne(ctx, rec)
+			}
+		}()
+	}
+
+feed:
+	for _, rec := range records {
+		select {
+		case jobs <- rec:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (f *Fetcher) fetchOne(ctx context.Context, rec Record) {
+	if rec.Digest != "" {
+		f.mu.Lock()
+		existing, dup := f.seen[rec.Digest]
+		f.mu.Unlock()
+
+		if dup {
+			f.writeManifest(ManifestEntry{
+				URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest,
+				Path: existing, Deduped: true,
+			})
+			return
+		}
+	}
+
+	if err := f.RateLimiter.Wait(ctx); err != nil {
+		return
+	}
+
+	snapshotURL := fmt.Sprintf("https://web.archive.org/web/%sid_/%s", rec.Timestamp, rec.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, snapshotURL, nil)
+	if err != nil {
+		f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Error: err.Error()})
+		return
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Error: err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Error: fmt.Sprintf("HTTP error: %d", resp.StatusCode)})
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Error: err.Error()})
+		return
+	}
+
+	path, err := f.write(rec, body)
+	if err != nil {
+		f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Error: err.Error()})
+		return
+	}
+
+	if rec.Digest != "" {
+		f.mu.Lock()
+		f.seen[rec.Digest] = path
+		f.mu.Unlock()
+	}
+
+	f.writeManifest(ManifestEntry{URL: rec.URL, Timestamp: rec.Timestamp, Digest: rec.Digest, Path: path, Bytes: len(body)})
+}
+
+func (f *Fetcher) write(rec Record, body []byte) (string, error) {
+	host := "unknown"
+	if parsed, err := url.Parse(rec.URL); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+
+	dir := filepath.Join(f.OutDir, host)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create host directory: %v", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.%s", rec.Timestamp, digestOrUnknown(rec.Digest), extensionFor(rec.MimeType))
+	path := filepath.Join(dir, name)
+
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %v", err)
+	}
+
+	return path, nil
+}
+
+func (f *Fetcher) writeManifest(entry ManifestEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = f.manifest.Encode(entry)
+}
+
+func digestOrUnknown(digest string) string {
+	if digest == "" {
+		return "nodigest"
+	}
+	return digest
+}
+
+// extensionFor maps a CDX mimetype column to a file extension, falling back
+// to "bin" for unknown or empty types.
+func extensionFor(mimeType string) string {
+	mimeType = strings.TrimSpace(strings.ToLower(mimeType))
+	if mimeType == "" {
+		return "bin"
+	}
+
+	switch mimeType {
+	case "text/html":
+		return "html"
+	case "text/plain":
+		return "txt"
+	case "text/css":
+		return "css"
+	case "text/xml", "application/xml":
+		return "xml"
+	case "application/json":
+		return "json"
+	case "application/javascript", "text/javascript":
+		return "js"
+	case "application/pdf":
+		return "pdf"
+	case "image/jpeg":
+		return "jpg"
+	case "image/png":
+		return "png"
+	case "image/gif":
+		return "gif"
+	case "image/svg+xml":
+		return "svg"
+	}
+
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return strings.TrimPrefix(exts[0], ".")
+	}
+
+	return "bin"
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -13,21 +16,44 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Abhinandan-Khurana/go-wayback/fetcher"
 )
 
+// errMaxResultsReached signals that a process*Format call stopped early
+// because config.MaxResults was hit, so the caller should stop paginating
+// rather than treat it as a failure.
+var errMaxResultsReached = errors.New("max results reached")
+
+// errDeadlineExceeded signals that a crawl was cut short by -maxtime or
+// -maxtime-job rather than failing outright; already-written output is
+// still valid, just incomplete.
+var errDeadlineExceeded = errors.New("crawl stopped: deadline exceeded")
+
+// defaultPageSize is used when -page-size is not set or is non-positive.
+const defaultPageSize = 10000
+
+// exitDeadlineExceeded is the process exit code used when a crawl was cut
+// short by a max-time deadline, distinguishing it from ordinary failures.
+const exitDeadlineExceeded = 2
+
 const (
 	VERSION = "v2.0.1"
 	AUTHOR  = "Abhinandan-Khurana"
 )
 
 type WaybackResult struct {
-	URL       string    `json:"url" xml:"url"`
-	Length    string    `json:"length" xml:"length"`
-	Timestamp string    `json:"timestamp" xml:"timestamp"`
-	Error     error     `json:"-" xml:"-"`
-	Date      time.Time `json:"date" xml:"date"`
+	URL        string    `json:"url" xml:"url"`
+	Length     string    `json:"length" xml:"length"`
+	Timestamp  string    `json:"timestamp" xml:"timestamp"`
+	StatusCode string    `json:"statusCode" xml:"statusCode"`
+	MimeType   string    `json:"mimeType" xml:"mimeType"`
+	Digest     string    `json:"digest" xml:"digest"`
+	Error      error     `json:"-" xml:"-"`
+	Date       time.Time `json:"date" xml:"date"`
 }
 
 type Config struct {
@@ -47,6 +73,24 @@ type Config struct {
 	RegexFilter  string
 	RateLimit    int
 	MaxResults   int
+	Resume       bool
+	PageSize     int
+	MatchStatus  string
+	FilterStatus string
+	MatchSize    string
+	FilterSize   string
+	MatchMime    string
+	FilterMime   string
+	MatchRegex   string
+	FilterRegex  string
+	FetchMode    bool
+	FetchDir     string
+	MaxTime      int
+	MaxTimeJob   int
+	LoggerFile   string
+	LogFormat    string
+	SourceList   string
+	ListSources  bool
 }
 
 type XMLResponse struct {
@@ -91,8 +135,14 @@ func newRateLimiter(requestsPerSecond int) *RateLimiter {
 	}
 }
 
-func (r *RateLimiter) Wait() {
-	<-r.ticker.C
+// Wait blocks until the next tick, or returns ctx.Err() if ctx is done first.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-r.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (r *RateLimiter) Stop() {
@@ -168,19 +218,44 @@ func matchesFilter(url string, regexPattern string) bool {
 	return regex.MatchString(url)
 }
 
-func processJSONFormat(bodyBytes []byte, config Config, writer io.Writer) error {
-	lines := strings.Split(string(bodyBytes), "\n")
-	var results []WaybackResult
-	uniqueURLs := make(map[string]bool)
-	count := 0
+// pageState carries the cross-page accumulator state a paginated crawl needs:
+// the dedup set, the running written count (for -max-results), whether a CSV
+// header has already been emitted, and (for the formats that must wrap their
+// output in a single document) the results collected so far.
+type pageState struct {
+	uniqueURLs map[string]bool
+	written    int
+	headerDone bool
+	results    []WaybackResult
+	matcher    *matcher
+}
+
+func newPageState(m *matcher) *pageState {
+	return &pageState{uniqueURLs: make(map[string]bool), matcher: m}
+}
 
-	for _, line := range lines {
+// parseStatusSize parses the CDX statuscode/length columns, defaulting to 0
+// when a field is missing or non-numeric (some CDX rows omit statuscode for
+// very old captures).
+func parseStatusSize(statusField, sizeField string) (status, size int) {
+	status, _ = strconv.Atoi(statusField)
+	size, _ = strconv.Atoi(sizeField)
+	return status, size
+}
+
+// processJSONFormat streams one CDX page from r, appending matching records
+// to state.results. The wrapping JSON document is only written once, after
+// all pages have been collected, since it carries a top-level count field.
+func processJSONFormat(r io.Reader, config Config, state *pageState) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 6 {
 			continue
 		}
 
@@ -189,49 +264,60 @@ func processJSONFormat(bodyBytes []byte, config Config, writer io.Writer) error
 			continue
 		}
 
+		statusCode, size := parseStatusSize(fields[3], fields[1])
+		if !state.matcher.allows(fields[0], statusCode, size, fields[4]) {
+			continue
+		}
+
 		// Handle unique URLs
 		if config.UniqueURLs {
-			if uniqueURLs[fields[0]] {
+			if state.uniqueURLs[fields[0]] {
 				continue
 			}
-			uniqueURLs[fields[0]] = true
+			state.uniqueURLs[fields[0]] = true
 		}
 
 		timestamp, _ := time.Parse("20060102150405", fields[2])
-		result := WaybackResult{
-			URL:       fields[0],
-			Length:    fields[1],
-			Timestamp: fields[2],
-			Date:      timestamp,
-		}
-
-		results = append(results, result)
-		count++
-
-		if config.MaxResults > 0 && count >= config.MaxResults {
-			break
+		state.results = append(state.results, WaybackResult{
+			URL:        fields[0],
+			Length:     fields[1],
+			Timestamp:  fields[2],
+			StatusCode: fields[3],
+			MimeType:   fields[4],
+			Digest:     fields[5],
+			Date:       timestamp,
+		})
+		state.written++
+
+		if config.MaxResults > 0 && state.written >= config.MaxResults {
+			return errMaxResultsReached
 		}
 	}
 
+	return scanner.Err()
+}
+
+// writeJSONResults encodes the final accumulated results once a crawl
+// (possibly spanning many pages) has finished.
+func writeJSONResults(state *pageState, writer io.Writer) error {
 	return json.NewEncoder(writer).Encode(map[string]interface{}{
-		"results": results,
-		"count":   len(results),
+		"results": state.results,
+		"count":   len(state.results),
 	})
 }
 
-func processXMLFormat(bodyBytes []byte, config Config, writer io.Writer) error {
-	lines := strings.Split(string(bodyBytes), "\n")
-	var results []WaybackResult
-	uniqueURLs := make(map[string]bool)
-	count := 0
-
-	for _, line := range lines {
+// processXMLFormat is the XML counterpart of processJSONFormat: it parses one
+// page and accumulates matching records, leaving encoding to writeXMLResults.
+func processXMLFormat(r io.Reader, config Config, state *pageState) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 6 {
 			continue
 		}
 
@@ -240,33 +326,43 @@ func processXMLFormat(bodyBytes []byte, config Config, writer io.Writer) error {
 			continue
 		}
 
+		statusCode, size := parseStatusSize(fields[3], fields[1])
+		if !state.matcher.allows(fields[0], statusCode, size, fields[4]) {
+			continue
+		}
+
 		// Handle unique URLs
 		if config.UniqueURLs {
-			if uniqueURLs[fields[0]] {
+			if state.uniqueURLs[fields[0]] {
 				continue
 			}
-			uniqueURLs[fields[0]] = true
+			state.uniqueURLs[fields[0]] = true
 		}
 
 		timestamp, _ := time.Parse("20060102150405", fields[2])
-		result := WaybackResult{
-			URL:       fields[0],
-			Length:    fields[1],
-			Timestamp: fields[2],
-			Date:      timestamp,
-		}
-
-		results = append(results, result)
-		count++
-
-		if config.MaxResults > 0 && count >= config.MaxResults {
-			break
+		state.results = append(state.results, WaybackResult{
+			URL:        fields[0],
+			Length:     fields[1],
+			Timestamp:  fields[2],
+			StatusCode: fields[3],
+			MimeType:   fields[4],
+			Digest:     fields[5],
+			Date:       timestamp,
+		})
+		state.written++
+
+		if config.MaxResults > 0 && state.written >= config.MaxResults {
+			return errMaxResultsReached
 		}
 	}
 
+	return scanner.Err()
+}
+
+func writeXMLResults(state *pageState, writer io.Writer) error {
 	xmlData := XMLResponse{
-		Results: results,
-		Count:   len(results),
+		Results: state.results,
+		Count:   len(state.results),
 	}
 
 	// Write XML header
@@ -277,25 +373,26 @@ func processXMLFormat(bodyBytes []byte, config Config, writer io.Writer) error {
 	return encoder.Encode(xmlData)
 }
 
-func processCSVFormat(bodyBytes []byte, config Config, writer io.Writer) error {
-	csvWriter := csv.NewWriter(writer)
-	defer csvWriter.Flush()
-
-	if err := csvWriter.Write([]string{"URL", "LENGTH", "TIMESTAMP", "DATE"}); err != nil {
-		return fmt.Errorf("error writing CSV header: %v", err)
+// processCSVFormat streams one CDX page straight into csvWriter. Unlike
+// JSON/XML, CSV has no wrapping document, so records (and, on the first
+// page, the header) are written immediately and survive a crash untouched.
+func processCSVFormat(r io.Reader, config Config, csvWriter *csv.Writer, state *pageState) error {
+	if !state.headerDone {
+		if err := csvWriter.Write([]string{"URL", "LENGTH", "TIMESTAMP", "DATE", "STATUSCODE", "MIMETYPE", "DIGEST"}); err != nil {
+			return fmt.Errorf("error writing CSV header: %v", err)
+		}
+		state.headerDone = true
 	}
 
-	lines := strings.Split(string(bodyBytes), "\n")
-	uniqueURLs := make(map[string]bool)
-	count := 0
-
-	for _, line := range lines {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 6 {
 			continue
 		}
 
@@ -304,12 +401,17 @@ func processCSVFormat(bodyBytes []byte, config Config, writer io.Writer) error {
 			continue
 		}
 
+		statusCode, size := parseStatusSize(fields[3], fields[1])
+		if !state.matcher.allows(fields[0], statusCode, size, fields[4]) {
+			continue
+		}
+
 		// Handle unique URLs
 		if config.UniqueURLs {
-			if uniqueURLs[fields[0]] {
+			if state.uniqueURLs[fields[0]] {
 				continue
 			}
-			uniqueURLs[fields[0]] = true
+			state.uniqueURLs[fields[0]] = true
 		}
 
 		timestamp, _ := time.Parse("20060102150405", fields[2])
@@ -318,33 +420,88 @@ func processCSVFormat(bodyBytes []byte, config Config, writer io.Writer) error {
 			fields[1],
 			fields[2],
 			timestamp.Format(time.RFC3339),
+			fields[3],
+			fields[4],
+			fields[5],
 		}
 
 		if err := csvWriter.Write(record); err != nil {
 			return fmt.Errorf("error writing CSV record: %v", err)
 		}
 
-		count++
-		if config.MaxResults > 0 && count >= config.MaxResults {
-			break
+		state.written++
+		if config.MaxResults > 0 && state.written >= config.MaxResults {
+			csvWriter.Flush()
+			return errMaxResultsReached
 		}
 	}
 
-	return nil
+	csvWriter.Flush()
+	return scanner.Err()
 }
 
-func processTextFormat(bodyBytes []byte, config Config, writer io.Writer) error {
-	lines := strings.Split(string(bodyBytes), "\n")
-	uniqueURLs := make(map[string]bool)
-	count := 0
+// collectFetchRecords applies the same filter/matcher/unique-URL rules as
+// the process*Format functions, but produces fetcher.Record values for
+// -fetch mode instead of writing formatted output.
+func collectFetchRecords(r io.Reader, config Config, state *pageState) ([]fetcher.Record, error) {
+	var records []fetcher.Record
 
-	for _, line := range lines {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
 		fields := strings.Fields(line)
-		if len(fields) < 3 {
+		if len(fields) < 6 {
+			continue
+		}
+
+		if !matchesFilter(fields[0], config.RegexFilter) {
+			continue
+		}
+
+		statusCode, size := parseStatusSize(fields[3], fields[1])
+		if !state.matcher.allows(fields[0], statusCode, size, fields[4]) {
+			continue
+		}
+
+		if config.UniqueURLs {
+			if state.uniqueURLs[fields[0]] {
+				continue
+			}
+			state.uniqueURLs[fields[0]] = true
+		}
+
+		records = append(records, fetcher.Record{
+			URL:       fields[0],
+			Timestamp: fields[2],
+			MimeType:  fields[4],
+			Digest:    fields[5],
+		})
+		state.written++
+
+		if config.MaxResults > 0 && state.written >= config.MaxResults {
+			return records, errMaxResultsReached
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+// processTextFormat streams one CDX page straight into writer, one URL per
+// line, so output up to the last completed page is always valid.
+func processTextFormat(r io.Reader, config Config, writer io.Writer, state *pageState) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
 			continue
 		}
 
@@ -353,12 +510,17 @@ func processTextFormat(bodyBytes []byte, config Config, writer io.Writer) error
 			continue
 		}
 
+		statusCode, size := parseStatusSize(fields[3], fields[1])
+		if !state.matcher.allows(fields[0], statusCode, size, fields[4]) {
+			continue
+		}
+
 		// Handle unique URLs
 		if config.UniqueURLs {
-			if uniqueURLs[fields[0]] {
+			if state.uniqueURLs[fields[0]] {
 				continue
 			}
-			uniqueURLs[fields[0]] = true
+			state.uniqueURLs[fields[0]] = true
 		}
 
 		outputURL := fields[0]
@@ -368,121 +530,343 @@ func processTextFormat(bodyBytes []byte, config Config, writer io.Writer) error
 
 		fmt.Fprintln(writer, outputURL)
 
-		count++
-		if config.MaxResults > 0 && count >= config.MaxResults {
-			break
+		state.written++
+		if config.MaxResults > 0 && state.written >= config.MaxResults {
+			return errMaxResultsReached
 		}
 	}
 
-	if config.Verbose {
-		fmt.Fprintf(os.Stderr, "Total URLs processed: %d\n", count)
+	return scanner.Err()
+}
+
+// buildCDXQuery constructs the paginated CDX API request for inputURL.
+// resumeKey, when non-empty, continues a prior page; limit bounds the
+// number of rows the server returns per page.
+func buildCDXQuery(inputURL string, limit int, resumeKey string) string {
+	escapedURL := url.QueryEscape("*." + inputURL + "/*")
+	apiURL := fmt.Sprintf(
+		"https://web.archive.org/cdx/search/cdx?url=%s&fl=original,length,timestamp,statuscode,mimetype,digest&limit=%d&showResumeKey=true",
+		escapedURL, limit,
+	)
+	if resumeKey != "" {
+		apiURL += "&resumeKey=" + url.QueryEscape(resumeKey)
 	}
+	return apiURL
+}
 
-	return nil
+// splitResumeKey separates a CDX page body from its trailing resume key.
+// When a page is not the last one, the CDX API appends a blank line
+// followed by the resume key after the record rows.
+func splitResumeKey(body []byte) (page []byte, resumeKey string) {
+	trimmed := bytes.TrimRight(body, "\n")
+	idx := bytes.LastIndex(trimmed, []byte("\n\n"))
+	if idx == -1 {
+		return body, ""
+	}
+	return trimmed[:idx], strings.TrimSpace(string(trimmed[idx+2:]))
+}
+
+// sleepCtx sleeps for d, returning early with ctx.Err() if ctx is canceled
+// or its deadline expires first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetchCDXPage performs a single CDX request, retrying with exponential
+// backoff on 429/5xx responses (honoring Retry-After when present) and on
+// transient network errors. It honors ctx both for the request itself and
+// for the retry backoff.
+func fetchCDXPage(ctx context.Context, client *http.Client, apiURL string, config Config) (*http.Response, error) {
+	const maxRetries = 5
+	backoff := time.Second
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request: %v", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if attempt >= maxRetries {
+				return nil, fmt.Errorf("failed to fetch data after %d attempts: %v", attempt+1, err)
+			}
+			logr.Warn("CDX request failed, retrying", map[string]interface{}{
+				"url": apiURL, "attempt": attempt + 1, "backoff": backoff.String(), "error": err.Error(),
+			})
+			if serr := sleepCtx(ctx, backoff); serr != nil {
+				return nil, serr
+			}
+			backoff *= 2
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			wait := backoff
+			if ra := resp.Header.Get("Retry-After"); ra != "" {
+				if secs, err := strconv.Atoi(ra); err == nil {
+					wait = time.Duration(secs) * time.Second
+				}
+			}
+			resp.Body.Close()
+
+			if attempt >= maxRetries {
+				return nil, fmt.Errorf("HTTP error %d after %d attempts", resp.StatusCode, attempt+1)
+			}
+			logr.Warn("CDX request rate limited or failed, retrying", map[string]interface{}{
+				"url": apiURL, "status": resp.StatusCode, "attempt": attempt + 1, "wait": wait.String(),
+			})
+			if serr := sleepCtx(ctx, wait); serr != nil {
+				return nil, serr
+			}
+			backoff *= 2
+			continue
+		}
+
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
 }
 
-func processURL(inputURL string, config Config, rateLimiter *RateLimiter) error {
+func processURL(ctx context.Context, inputURL string, config Config, rateLimiter *RateLimiter) error {
 	if !strings.HasPrefix(inputURL, "http://") && !strings.HasPrefix(inputURL, "https://") {
 		inputURL = "http://" + inputURL
 	}
 
-	escapedURL := url.QueryEscape("*." + inputURL + "/*")
-	apiURL := fmt.Sprintf("https://web.archive.org/cdx/search/cdx?url=%s&fl=original,length,timestamp", escapedURL)
+	pageSize := config.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
 
-	if config.Verbose {
-		fmt.Fprintf(os.Stderr, "Fetching data from: %s\n", apiURL)
+	m, err := newMatcher(config)
+	if err != nil {
+		return fmt.Errorf("invalid matcher flags: %v", err)
 	}
 
-	rateLimiter.Wait()
+	var statePath string
+	var resumeKey string
+	if config.OutputFile != "" {
+		statePath = checkpointPath(config.OutputFile)
+	}
+	if config.Resume && statePath != "" {
+		cp, err := loadCheckpoint(statePath)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %v", err)
+		}
+		if cp != nil {
+			resumeKey = cp.ResumeKey
+			logr.Info("resuming crawl from checkpoint", map[string]interface{}{
+				"url": inputURL, "checkpointSavedAt": cp.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+	}
+
+	// json/xml write a single top-level document built from state.results, so
+	// appending a freshly-resumed run's partial results after a prior run's
+	// complete document would produce two concatenated top-level values,
+	// not one valid document. Only the streaming formats (text/csv) can
+	// safely resume by appending.
+	if resumeKey != "" {
+		switch strings.ToLower(config.OutputFormat) {
+		case "json", "xml":
+			return fmt.Errorf("-resume is not supported with -format %s; use text or csv", config.OutputFormat)
+		}
+	}
+
+	var writer io.Writer = os.Stdout
+	if config.OutputFile != "" {
+		flags := os.O_CREATE | os.O_WRONLY
+		if resumeKey != "" {
+			flags |= os.O_APPEND
+		} else {
+			flags |= os.O_TRUNC
+		}
+		file, err := os.OpenFile(config.OutputFile, flags, 0644)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer file.Close()
+		writer = file
+	}
 
 	client := &http.Client{
 		Timeout: time.Duration(config.Timeout) * time.Second,
 	}
 
-	resp, err := client.Get(apiURL)
-	if err != nil {
-		return fmt.Errorf("failed to fetch data: %v", err)
+	state := newPageState(m)
+	var csvWriter *csv.Writer
+	if strings.ToLower(config.OutputFormat) == "csv" {
+		csvWriter = csv.NewWriter(writer)
+		if resumeKey != "" {
+			state.headerDone = true
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP error: %d", resp.StatusCode)
-	}
+	subdomains := make(map[string]bool)
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+	var snap *fetcher.Fetcher
+	if config.FetchMode {
+		snap, err = fetcher.New(config.FetchDir, config.Concurrent, rateLimiter)
+		if err != nil {
+			return err
+		}
+		defer snap.Close()
 	}
 
-	// Handle subdomain mode separately
-	if config.Subdomain {
-		subdomains := make(map[string]bool)
-		lines := strings.Split(string(bodyBytes), "\n")
+	page := 0
+	cancelled := false
+	for {
+		page++
+		apiURL := buildCDXQuery(inputURL, pageSize, resumeKey)
 
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
+		logr.Debug("fetching CDX page", map[string]interface{}{"url": inputURL, "page": page, "apiURL": apiURL})
+
+		if err := rateLimiter.Wait(ctx); err != nil {
+			cancelled = true
+			break
+		}
+
+		pageStart := time.Now()
+		resp, err := fetchCDXPage(ctx, client, apiURL, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				cancelled = true
+				break
 			}
+			logr.Error("CDX request failed", map[string]interface{}{"url": inputURL, "apiURL": apiURL, "error": err.Error()})
+			return err
+		}
 
-			fields := strings.Fields(line)
-			if len(fields) < 1 {
-				continue
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %v", err)
+		}
+
+		logr.Info("fetched CDX page", map[string]interface{}{
+			"url": inputURL, "page": page, "status": resp.StatusCode,
+			"bytes": len(bodyBytes), "elapsed": time.Since(pageStart).String(),
+		})
+
+		pageBody, nextResumeKey := splitResumeKey(bodyBytes)
+
+		var perr error
+		if config.Subdomain {
+			scanner := bufio.NewScanner(bytes.NewReader(pageBody))
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) < 1 {
+					continue
+				}
+				if subdomain := extractSubdomains(fields[0]); subdomain != "" {
+					subdomains[subdomain] = true
+				}
+			}
+			perr = scanner.Err()
+		} else if config.FetchMode {
+			var records []fetcher.Record
+			records, perr = collectFetchRecords(bytes.NewReader(pageBody), config, state)
+			if len(records) > 0 {
+				if err := snap.Fetch(ctx, records); err != nil {
+					if ctx.Err() != nil {
+						cancelled = true
+					} else {
+						return err
+					}
+				}
 			}
+		} else {
+			switch strings.ToLower(config.OutputFormat) {
+			case "json":
+				perr = processJSONFormat(bytes.NewReader(pageBody), config, state)
+			case "xml":
+				perr = processXMLFormat(bytes.NewReader(pageBody), config, state)
+			case "csv":
+				perr = processCSVFormat(bytes.NewReader(pageBody), config, csvWriter, state)
+			default:
+				perr = processTextFormat(bytes.NewReader(pageBody), config, writer, state)
+			}
+		}
+		if perr != nil && perr != errMaxResultsReached {
+			return perr
+		}
 
-			subdomain := extractSubdomains(fields[0])
-			if subdomain != "" {
-				subdomains[subdomain] = true
+		if statePath != "" && !config.Subdomain {
+			if err := saveCheckpoint(statePath, checkpoint{ResumeKey: nextResumeKey, UpdatedAt: time.Now()}); err != nil {
+				logr.Warn("failed to save checkpoint", map[string]interface{}{"url": inputURL, "error": err.Error()})
 			}
 		}
 
+		if perr == errMaxResultsReached || nextResumeKey == "" || cancelled {
+			break
+		}
+		resumeKey = nextResumeKey
+	}
+
+	if config.Subdomain {
 		var uniqueSubdomains []string
 		for subdomain := range subdomains {
 			uniqueSubdomains = append(uniqueSubdomains, subdomain)
 		}
 		sort.Strings(uniqueSubdomains)
 
-		var writer io.Writer = os.Stdout
-		if config.OutputFile != "" {
-			file, err := os.Create(config.OutputFile)
-			if err != nil {
-				return fmt.Errorf("error creating output file: %v", err)
-			}
-			defer file.Close()
-			writer = file
-		}
-
 		for _, subdomain := range uniqueSubdomains {
 			fmt.Fprintln(writer, subdomain)
 		}
 
-		if config.Verbose {
-			fmt.Fprintf(os.Stderr, "Total unique subdomains found: %d\n", len(uniqueSubdomains))
-		}
+		logr.Info("finished subdomain crawl", map[string]interface{}{"url": inputURL, "uniqueSubdomains": len(uniqueSubdomains)})
 
+		if cancelled {
+			return errDeadlineExceeded
+		}
 		return nil
 	}
 
-	// Process the response based on format
-	var writer io.Writer = os.Stdout
-	if config.OutputFile != "" {
-		file, err := os.Create(config.OutputFile)
-		if err != nil {
-			return fmt.Errorf("error creating output file: %v", err)
+	if !config.FetchMode {
+		switch strings.ToLower(config.OutputFormat) {
+		case "json":
+			if err := writeJSONResults(state, writer); err != nil {
+				return err
+			}
+		case "xml":
+			if err := writeXMLResults(state, writer); err != nil {
+				return err
+			}
 		}
-		defer file.Close()
-		writer = file
 	}
 
-	switch strings.ToLower(config.OutputFormat) {
-	case "json":
-		return processJSONFormat(bodyBytes, config, writer)
-	case "xml":
-		return processXMLFormat(bodyBytes, config, writer)
-	case "csv":
-		return processCSVFormat(bodyBytes, config, writer)
-	default:
-		return processTextFormat(bodyBytes, config, writer)
+	// A checkpoint is only cleared on a clean finish; if the crawl was cut
+	// short by a deadline, keep it around so -resume can pick up from here.
+	if statePath != "" && !cancelled {
+		if err := clearCheckpoint(statePath); err != nil {
+			logr.Warn("failed to clear checkpoint", map[string]interface{}{"url": inputURL, "error": err.Error()})
+		}
 	}
+
+	if config.FetchMode {
+		logr.Info("finished fetch crawl", map[string]interface{}{"url": inputURL, "snapshotsFetched": state.written})
+	} else {
+		logr.Info("finished crawl", map[string]interface{}{"url": inputURL, "urlsProcessed": state.written})
+	}
+
+	if cancelled {
+		return errDeadlineExceeded
+	}
+	return nil
 }
 
 func main() {
@@ -504,6 +888,31 @@ func main() {
 	flag.StringVar(&config.RegexFilter, "filter", "", "Regex pattern to filter URLs")
 	flag.IntVar(&config.RateLimit, "rate-limit", 10, "Maximum requests per second")
 	flag.IntVar(&config.MaxResults, "max-results", 0, "Maximum number of results (0 for unlimited)")
+	flag.BoolVar(&config.Resume, "resume", false, "Resume a crawl from its saved checkpoint (requires -o)")
+	flag.IntVar(&config.PageSize, "page-size", defaultPageSize, "CDX results per page when paginating")
+
+	flag.StringVar(&config.MatchStatus, "mc", "", "Match HTTP status codes, e.g. 200-299,301")
+	flag.StringVar(&config.FilterStatus, "fc", "", "Filter out HTTP status codes, e.g. 404,410")
+	flag.StringVar(&config.MatchSize, "ms", "", "Match content length in bytes, e.g. 100-500 or >1024")
+	flag.StringVar(&config.FilterSize, "fs", "", "Filter out content length in bytes")
+	flag.StringVar(&config.MatchSize, "ml", "", "Alias for -ms (ffuf-style word-count flag name; CDX rows have no body to count words in)")
+	flag.StringVar(&config.FilterSize, "fl", "", "Alias for -fs")
+	flag.StringVar(&config.MatchMime, "mt", "", "Match MIME type globs, e.g. text/*,application/pdf")
+	flag.StringVar(&config.FilterMime, "ft", "", "Filter out MIME type globs")
+	flag.StringVar(&config.MatchRegex, "mr", "", "Match URLs against a regex")
+	flag.StringVar(&config.FilterRegex, "fr", "", "Filter out URLs matching a regex")
+
+	flag.BoolVar(&config.FetchMode, "fetch", false, "Download archived response bodies instead of listing URLs")
+	flag.StringVar(&config.FetchDir, "fetch-dir", "out", "Directory to write fetched snapshots and manifest into")
+
+	flag.IntVar(&config.MaxTime, "maxtime", 0, "Whole-process wall clock budget in seconds (0 for unlimited)")
+	flag.IntVar(&config.MaxTimeJob, "maxtime-job", 0, "Per-input-URL wall clock budget in seconds (0 for unlimited)")
+
+	flag.StringVar(&config.LoggerFile, "logger", "", "Write logs to this file instead of stderr")
+	flag.StringVar(&config.LogFormat, "log-format", "text", "Log output format (text/json)")
+
+	flag.StringVar(&config.SourceList, "source", "wayback", "Comma-separated archive sources to query (see -list-sources)")
+	flag.BoolVar(&config.ListSources, "list-sources", false, "List supported archive sources and exit")
 
 	version := flag.Bool("version", false, "Display version information")
 	help := flag.Bool("h", false, "Display help")
@@ -515,11 +924,32 @@ func main() {
 		return
 	}
 
+	if config.ListSources {
+		printSources()
+		return
+	}
+
 	if *help || (flag.NArg() == 0 && config.InputFile == "") {
 		printHelp()
 		return
 	}
 
+	sources, err := parseSources(config.SourceList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	appLog, logCloser, err := newAppLogger(config.LoggerFile, config.LogFormat, config.Verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	logr = appLog
+	if logCloser != nil {
+		defer logCloser.Close()
+	}
+
 	rateLimiter := newRateLimiter(config.RateLimit)
 	defer rateLimiter.Stop()
 
@@ -528,17 +958,54 @@ func main() {
 		var err error
 		urls, err = loadURLsFromFile(config.InputFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading URLs from file: %v\n", err)
+			logr.Error("failed to load URLs from file", map[string]interface{}{"file": config.InputFile, "error": err.Error()})
 			os.Exit(1)
 		}
 	} else {
 		urls = []string{flag.Arg(0)}
 	}
 
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if config.MaxTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.MaxTime)*time.Second)
+		defer cancel()
+	}
+
+	exitCode := 0
 	for _, inputURL := range urls {
-		if err := processURL(inputURL, config, rateLimiter); err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", inputURL, err)
+		jobCtx := ctx
+		var jobCancel context.CancelFunc
+		if config.MaxTimeJob > 0 {
+			jobCtx, jobCancel = context.WithTimeout(ctx, time.Duration(config.MaxTimeJob)*time.Second)
 		}
+
+		var err error
+		if len(sources) == 1 && sources[0].Name() == "wayback" {
+			err = processURL(jobCtx, inputURL, config, rateLimiter)
+		} else {
+			err = processURLMultiSource(jobCtx, inputURL, config, rateLimiter, sources)
+		}
+		if jobCancel != nil {
+			jobCancel()
+		}
+
+		if err != nil {
+			logr.Error("error processing URL", map[string]interface{}{"url": inputURL, "error": err.Error()})
+			if errors.Is(err, errDeadlineExceeded) {
+				exitCode = exitDeadlineExceeded
+			} else if exitCode == 0 {
+				exitCode = 1
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
 }
 
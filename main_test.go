@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSplitResumeKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantPage      string
+		wantResumeKey string
+	}{
+		{
+			name:          "no resume key",
+			body:          "row one\nrow two\n",
+			wantPage:      "row one\nrow two\n",
+			wantResumeKey: "",
+		},
+		{
+			name:          "with resume key",
+			body:          "row one\nrow two\n\nsome-resume-key\n",
+			wantPage:      "row one\nrow two",
+			wantResumeKey: "some-resume-key",
+		},
+		{
+			name:          "empty body",
+			body:          "",
+			wantPage:      "",
+			wantResumeKey: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, resumeKey := splitResumeKey([]byte(tt.body))
+			if string(page) != tt.wantPage {
+				t.Errorf("page = %q, want %q", page, tt.wantPage)
+			}
+			if resumeKey != tt.wantResumeKey {
+				t.Errorf("resumeKey = %q, want %q", resumeKey, tt.wantResumeKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rangeCond is a single numeric match condition: an exact value, an
+// inclusive [min,max] range, or an open-ended ">min"/"<max" bound.
+type rangeCond struct {
+	exact *int
+	min   *int
+	max   *int
+}
+
+func (c rangeCond) matches(v int) bool {
+	if c.exact != nil {
+		return v == *c.exact
+	}
+	if c.min != nil && v < *c.min {
+		return false
+	}
+	if c.max != nil && v > *c.max {
+		return false
+	}
+	return true
+}
+
+// parseRangeList parses a comma-separated list of ffuf-style numeric
+// conditions, e.g. "200-299,301", ">1024", "<50".
+func parseRangeList(s string) ([]rangeCond, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var conds []rangeCond
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(part, ">"):
+			n, err := strconv.Atoi(strings.TrimSpace(part[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", part, err)
+			}
+			min := n + 1
+			conds = append(conds, rangeCond{min: &min})
+		case strings.HasPrefix(part, "<"):
+			n, err := strconv.Atoi(strings.TrimSpace(part[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", part, err)
+			}
+			max := n - 1
+			conds = append(conds, rangeCond{max: &max})
+		case strings.Contains(part, "-") && !strings.HasPrefix(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", part, err)
+			}
+			hi, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid range %q: %v", part, err)
+			}
+			conds = append(conds, rangeCond{min: &lo, max: &hi})
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q: %v", part, err)
+			}
+			conds = append(conds, rangeCond{exact: &n})
+		}
+	}
+
+	return conds, nil
+}
+
+func matchesAny(conds []rangeCond, v int) bool {
+	if len(conds) == 0 {
+		return true
+	}
+	for _, c := range conds {
+		if c.matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseGlobList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var globs []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			globs = append(globs, part)
+		}
+	}
+	return globs
+}
+
+func matchesAnyGlob(globs []string, mimeType string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, g := range globs {
+		if ok, _ := path.Match(g, mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectsAny reports whether v should be rejected by a set of -f* filter
+// conditions. Unlike matchesAny (used for -m* match conditions, which
+// accept everything when unset), an unset filter list must never reject
+// anything, so it short-circuits to false instead of matchesAny's true.
+func rejectsAny(conds []rangeCond, v int) bool {
+	return len(conds) > 0 && matchesAny(conds, v)
+}
+
+// rejectsAnyGlob is rejectsAny's counterpart for -ft/-fr-style MIME globs.
+func rejectsAnyGlob(globs []string, mimeType string) bool {
+	return len(globs) > 0 && matchesAnyGlob(globs, mimeType)
+}
+
+// matcher implements the ffuf-style matcher/filter split: a record is kept
+// when it satisfies every match condition and none of the filter
+// conditions. Match conditions with no value configured are treated as
+// "accept anything"; filter conditions with no value configured never
+// reject anything.
+type matcher struct {
+	matchStatus  []rangeCond
+	filterStatus []rangeCond
+	matchSize    []rangeCond
+	filterSize   []rangeCond
+	matchMime    []string
+	filterMime   []string
+	matchRegex   *regexp.Regexp
+	filterRegex  *regexp.Regexp
+}
+
+// newMatcher builds a matcher from the raw -mc/-ms/-mr/-mt and
+// -fc/-fs/-ft/-fr flag values. -ml/-fl are aliases for -ms/-fs (see the
+// flag registration in main) rather than a distinct condition list, since
+// CDX index rows have no response body to count words in.
+func newMatcher(config Config) (*matcher, error) {
+	m := &matcher{}
+	var err error
+
+	if m.matchStatus, err = parseRangeList(config.MatchStatus); err != nil {
+		return nil, fmt.Errorf("-mc: %v", err)
+	}
+	if m.filterStatus, err = parseRangeList(config.FilterStatus); err != nil {
+		return nil, fmt.Errorf("-fc: %v", err)
+	}
+	if m.matchSize, err = parseRangeList(config.MatchSize); err != nil {
+		return nil, fmt.Errorf("-ms/-ml: %v", err)
+	}
+	if m.filterSize, err = parseRangeList(config.FilterSize); err != nil {
+		return nil, fmt.Errorf("-fs/-fl: %v", err)
+	}
+
+	m.matchMime = parseGlobList(config.MatchMime)
+	m.filterMime = parseGlobList(config.FilterMime)
+
+	if config.MatchRegex != "" {
+		if m.matchRegex, err = regexp.Compile(config.MatchRegex); err != nil {
+			return nil, fmt.Errorf("-mr: %v", err)
+		}
+	}
+	if config.FilterRegex != "" {
+		if m.filterRegex, err = regexp.Compile(config.FilterRegex); err != nil {
+			return nil, fmt.Errorf("-fr: %v", err)
+		}
+	}
+
+	return m, nil
+}
+
+// allows reports whether a CDX record passes every match condition and none
+// of the filter conditions. size is the CDX "length" column.
+func (m *matcher) allows(recordURL string, statusCode, size int, mimeType string) bool {
+	if !matchesAny(m.matchStatus, statusCode) {
+		return false
+	}
+	if rejectsAny(m.filterStatus, statusCode) {
+		return false
+	}
+	if !matchesAny(m.matchSize, size) {
+		return false
+	}
+	if rejectsAny(m.filterSize, size) {
+		return false
+	}
+	if !matchesAnyGlob(m.matchMime, mimeType) {
+		return false
+	}
+	if rejectsAnyGlob(m.filterMime, mimeType) {
+		return false
+	}
+	if m.matchRegex != nil && !m.matchRegex.MatchString(recordURL) {
+		return false
+	}
+	if m.filterRegex != nil && m.filterRegex.MatchString(recordURL) {
+		return false
+	}
+	return true
+}
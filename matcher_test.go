@@ -0,0 +1,155 @@
+package main
+
+import "testing"
+
+func TestParseRangeList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantLen int
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"exact", "200", 1, false},
+		{"range", "200-299", 1, false},
+		{"open min", ">1024", 1, false},
+		{"open max", "<50", 1, false},
+		{"list", "200-299,301,404", 3, false},
+		{"invalid exact", "abc", 0, true},
+		{"invalid range", "200-abc", 0, true},
+		{"invalid open min", ">abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, err := parseRangeList(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseRangeList(%q): expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRangeList(%q): unexpected error: %v", tt.input, err)
+			}
+			if len(conds) != tt.wantLen {
+				t.Fatalf("parseRangeList(%q): got %d conditions, want %d", tt.input, len(conds), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		v     int
+		want  bool
+	}{
+		{"no conditions always match", "", 42, true},
+		{"exact match", "200", 200, true},
+		{"exact no match", "200", 404, false},
+		{"range match", "200-299", 250, true},
+		{"range boundary", "200-299", 299, true},
+		{"range no match", "200-299", 300, false},
+		{"open min match", ">1024", 2048, true},
+		{"open min boundary excluded", ">1024", 1024, false},
+		{"open max match", "<50", 10, true},
+		{"open max boundary excluded", "<50", 50, false},
+		{"list match second", "200,404", 404, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, err := parseRangeList(tt.input)
+			if err != nil {
+				t.Fatalf("parseRangeList(%q): unexpected error: %v", tt.input, err)
+			}
+			if got := matchesAny(conds, tt.v); got != tt.want {
+				t.Errorf("matchesAny(%q, %d) = %v, want %v", tt.input, tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectsAnyDefaultsToFalse(t *testing.T) {
+	// An unset filter condition list must never reject a record; only
+	// matchesAny (used for -m* match conditions) defaults to "accept
+	// anything" for an empty list.
+	if rejectsAny(nil, 200) {
+		t.Error("rejectsAny(nil, 200) = true, want false (no filter configured)")
+	}
+	if rejectsAnyGlob(nil, "text/html") {
+		t.Error("rejectsAnyGlob(nil, ...) = true, want false (no filter configured)")
+	}
+
+	conds, err := parseRangeList("404")
+	if err != nil {
+		t.Fatalf("parseRangeList: unexpected error: %v", err)
+	}
+	if !rejectsAny(conds, 404) {
+		t.Error("rejectsAny: expected configured filter to reject a matching value")
+	}
+	if rejectsAny(conds, 200) {
+		t.Error("rejectsAny: expected configured filter to allow a non-matching value")
+	}
+}
+
+func TestMatcherAllowsWithNoFiltersConfigured(t *testing.T) {
+	// Regression test: allows() must not reject records when no -f* flags
+	// are set at all (this previously rejected everything, since the
+	// shared matchesAny default of "true" for an empty list was being
+	// reused as the filter's "should reject" signal).
+	m, err := newMatcher(Config{MatchStatus: "200-299"})
+	if err != nil {
+		t.Fatalf("newMatcher: unexpected error: %v", err)
+	}
+
+	if !m.allows("https://example.com/", 200, 1024, "text/html") {
+		t.Error("expected record to be allowed when no filters are configured")
+	}
+}
+
+func TestMatcherAllows(t *testing.T) {
+	config := Config{
+		MatchStatus: "200-299",
+		FilterMime:  "image/*",
+	}
+
+	m, err := newMatcher(config)
+	if err != nil {
+		t.Fatalf("newMatcher: unexpected error: %v", err)
+	}
+
+	if !m.allows("https://example.com/", 200, 1024, "text/html") {
+		t.Error("expected matching status and non-image mime to be allowed")
+	}
+	if m.allows("https://example.com/", 404, 1024, "text/html") {
+		t.Error("expected non-matching status to be rejected")
+	}
+	if m.allows("https://example.com/logo.png", 200, 1024, "image/png") {
+		t.Error("expected filtered mime type to be rejected")
+	}
+}
+
+func TestMatcherMlFlAliasMatchSize(t *testing.T) {
+	// -ml/-fl are aliases for -ms/-fs (see the flag registration in main),
+	// so populating MatchSize/FilterSize directly must drive the same
+	// condition list -ml/-fl would.
+	config := Config{MatchSize: "100-200", FilterSize: "150"}
+
+	m, err := newMatcher(config)
+	if err != nil {
+		t.Fatalf("newMatcher: unexpected error: %v", err)
+	}
+
+	if !m.allows("https://example.com/", 200, 120, "text/html") {
+		t.Error("expected size within match range to be allowed")
+	}
+	if m.allows("https://example.com/", 200, 150, "text/html") {
+		t.Error("expected filtered size to be rejected")
+	}
+	if m.allows("https://example.com/", 200, 300, "text/html") {
+		t.Error("expected size outside match range to be rejected")
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceQueryOptions parameterizes a Source's BuildQuery call.
+type SourceQueryOptions struct {
+	Limit     int
+	ResumeKey string
+}
+
+// SourceRequest is a single HTTP request a Source wants issued.
+type SourceRequest struct {
+	URL string
+}
+
+// Source abstracts over an archive index backend (Wayback CDX, Common
+// Crawl, arquivo.pt, ...). BuildQuery turns an input URL into the concrete
+// requests needed to query that backend, and ParseLine turns one line of
+// that backend's CDX-style response into a WaybackResult.
+type Source interface {
+	Name() string
+	BuildQuery(inputURL string, opts SourceQueryOptions) ([]SourceRequest, error)
+	ParseLine(line string) (WaybackResult, error)
+}
+
+// parseCDXLine parses the six space-separated CDX columns
+// (original,length,timestamp,statuscode,mimetype,digest) shared by Wayback,
+// arquivo.pt, and Common Crawl when queried with the same fl= parameter.
+func parseCDXLine(line string) (WaybackResult, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 6 {
+		return WaybackResult{}, fmt.Errorf("malformed CDX line: %q", line)
+	}
+
+	timestamp, _ := time.Parse("20060102150405", fields[2])
+	return WaybackResult{
+		URL:        fields[0],
+		Length:     fields[1],
+		Timestamp:  fields[2],
+		StatusCode: fields[3],
+		MimeType:   fields[4],
+		Digest:     fields[5],
+		Date:       timestamp,
+	}, nil
+}
+
+// waybackSource is the original Internet Archive Wayback Machine CDX API.
+type waybackSource struct{}
+
+func (waybackSource) Name() string { return "wayback" }
+
+func (waybackSource) BuildQuery(inputURL string, opts SourceQueryOptions) ([]SourceRequest, error) {
+	return []SourceRequest{{URL: buildCDXQuery(inputURL, opts.Limit, opts.ResumeKey)}}, nil
+}
+
+func (waybackSource) ParseLine(line string) (WaybackResult, error) {
+	return parseCDXLine(line)
+}
+
+// arquivoSource is arquivo.pt's OpenWayback-compatible CDX endpoint.
+type arquivoSource struct{}
+
+func (arquivoSource) Name() string { return "arquivo" }
+
+func (arquivoSource) BuildQuery(inputURL string, opts SourceQueryOptions) ([]SourceRequest, error) {
+	escapedURL := url.QueryEscape("*." + inputURL + "/*")
+	apiURL := fmt.Sprintf(
+		"https://arquivo.pt/wayback/cdx?url=%s&fl=original,length,timestamp,statuscode,mimetype,digest&limit=%d&showResumeKey=true",
+		escapedURL, opts.Limit,
+	)
+	if opts.ResumeKey != "" {
+		apiURL += "&resumeKey=" + url.QueryEscape(opts.ResumeKey)
+	}
+	return []SourceRequest{{URL: apiURL}}, nil
+}
+
+func (arquivoSource) ParseLine(line string) (WaybackResult, error) {
+	return parseCDXLine(line)
+}
+
+// commonCrawlCollection is the subset of index.commoncrawl.org/collinfo.json
+// this package cares about.
+type commonCrawlCollection struct {
+	ID     string `json:"id"`
+	CDXAPI string `json:"cdx-api"`
+}
+
+// commonCrawlSource queries every crawl index Common Crawl currently
+// publishes. Unlike Wayback/arquivo, Common Crawl has no single endpoint to
+// resume across; each index is queried once per call, so very large result
+// sets are capped at opts.Limit rows per index rather than paginated.
+type commonCrawlSource struct{}
+
+func (commonCrawlSource) Name() string { return "commoncrawl" }
+
+func (commonCrawlSource) BuildQuery(inputURL string, opts SourceQueryOptions) ([]SourceRequest, error) {
+	indexes, err := discoverCommonCrawlIndexes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover Common Crawl indexes: %v", err)
+	}
+
+	escapedURL := url.QueryEscape("*." + inputURL + "/*")
+	requests := make([]SourceRequest, 0, len(indexes))
+	for _, cdxAPI := range indexes {
+		requests = append(requests, SourceRequest{
+			URL: fmt.Sprintf("%s?url=%s&fl=original,length,timestamp,statuscode,mimetype,digest&limit=%d&output=text", cdxAPI, escapedURL, opts.Limit),
+		})
+	}
+	return requests, nil
+}
+
+func (commonCrawlSource) ParseLine(line string) (WaybackResult, error) {
+	return parseCDXLine(line)
+}
+
+func discoverCommonCrawlIndexes() ([]string, error) {
+	resp, err := http.Get("https://index.commoncrawl.org/collinfo.json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	var collections []commonCrawlCollection
+	if err := json.NewDecoder(resp.Body).Decode(&collections); err != nil {
+		return nil, fmt.Errorf("failed to parse collinfo.json: %v", err)
+	}
+
+	indexes := make([]string, 0, len(collections))
+	for _, c := range collections {
+		if c.CDXAPI != "" {
+			indexes = append(indexes, c.CDXAPI)
+		}
+	}
+	return indexes, nil
+}
+
+// sourceRegistry lists every Source this build supports, keyed by the name
+// used with -source.
+var sourceRegistry = map[string]Source{
+	"wayback":     waybackSource{},
+	"commoncrawl": commonCrawlSource{},
+	"arquivo":     arquivoSource{},
+}
+
+// parseSources resolves a comma-separated -source value into Source
+// implementations, defaulting to wayback-only when raw is empty.
+func parseSources(raw string) ([]Source, error) {
+	if strings.TrimSpace(raw) == "" {
+		return []Source{sourceRegistry["wayback"]}, nil
+	}
+
+	var sources []Source
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		src, ok := sourceRegistry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q (see -list-sources)", name)
+		}
+		sources = append(sources, src)
+	}
+
+	if len(sources) == 0 {
+		return []Source{sourceRegistry["wayback"]}, nil
+	}
+	return sources, nil
+}
+
+func sourceNames(sources []Source) []string {
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// printSources lists every registered source name, for -list-sources.
+func printSources() {
+	names := make([]string, 0, len(sourceRegistry))
+	for name := range sourceRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+// writeMultiSourceResults renders an already-merged result set in the
+// configured output format. Unlike the single-source pipeline, multi-source
+// crawls are not paginated/streamed, since sources have incompatible
+// pagination models; results are collected fully before writing.
+func writeMultiSourceResults(results []WaybackResult, config Config, writer io.Writer) error {
+	switch strings.ToLower(config.OutputFormat) {
+	case "json":
+		return writeJSONResults(&pageState{results: results}, writer)
+	case "xml":
+		return writeXMLResults(&pageState{results: results}, writer)
+	case "csv":
+		csvWriter := csv.NewWriter(writer)
+		defer csvWriter.Flush()
+		if err := csvWriter.Write([]string{"URL", "LENGTH", "TIMESTAMP", "DATE", "STATUSCODE", "MIMETYPE", "DIGEST"}); err != nil {
+			return fmt.Errorf("error writing CSV header: %v", err)
+		}
+		for _, r := range results {
+			record := []string{r.URL, r.Length, r.Timestamp, r.Date.Format(time.RFC3339), r.StatusCode, r.MimeType, r.Digest}
+			if err := csvWriter.Write(record); err != nil {
+				return fmt.Errorf("error writing CSV record: %v", err)
+			}
+		}
+		return nil
+	default:
+		for _, r := range results {
+			outputURL := r.URL
+			if config.Browsable {
+				outputURL = fmt.Sprintf("https://web.archive.org/web/%s/%s", r.Timestamp, r.URL)
+			}
+			fmt.Fprintln(writer, outputURL)
+		}
+		return nil
+	}
+}
+
+// processURLMultiSource fans a crawl out across every configured source,
+// merges and (optionally) dedupes the results, and writes them in one shot.
+func processURLMultiSource(ctx context.Context, inputURL string, config Config, rateLimiter *RateLimiter, sources []Source) error {
+	if config.Subdomain {
+		return fmt.Errorf("-subdomain is not supported with multiple/non-wayback -source values")
+	}
+	if config.FetchMode {
+		return fmt.Errorf("-fetch is not supported with multiple/non-wayback -source values")
+	}
+	if config.Resume {
+		return fmt.Errorf("-resume is not supported with multiple/non-wayback -source values")
+	}
+
+	if !strings.HasPrefix(inputURL, "http://") && !strings.HasPrefix(inputURL, "https://") {
+		inputURL = "http://" + inputURL
+	}
+
+	m, err := newMatcher(config)
+	if err != nil {
+		return fmt.Errorf("invalid matcher flags: %v", err)
+	}
+
+	pageSize := config.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+
+	var all []WaybackResult
+	seen := make(map[string]bool)
+	total := 0
+	cancelled := false
+
+outer:
+	for _, src := range sources {
+		requests, err := src.BuildQuery(inputURL, SourceQueryOptions{Limit: pageSize})
+		if err != nil {
+			logr.Warn("source query build failed", map[string]interface{}{"source": src.Name(), "url": inputURL, "error": err.Error()})
+			continue
+		}
+
+		for _, request := range requests {
+			if err := rateLimiter.Wait(ctx); err != nil {
+				cancelled = true
+				break outer
+			}
+
+			resp, err := fetchCDXPage(ctx, client, request.URL, config)
+			if err != nil {
+				if ctx.Err() != nil {
+					cancelled = true
+					break outer
+				}
+				logr.Warn("source request failed", map[string]interface{}{"source": src.Name(), "url": request.URL, "error": err.Error()})
+				continue
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				logr.Warn("failed reading source response", map[string]interface{}{"source": src.Name(), "error": err.Error()})
+				continue
+			}
+
+			page, _ := splitResumeKey(body)
+			scanner := bufio.NewScanner(bytes.NewReader(page))
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+
+				rec, err := src.ParseLine(line)
+				if err != nil {
+					continue
+				}
+
+				if !matchesFilter(rec.URL, config.RegexFilter) {
+					continue
+				}
+
+				statusCode, _ := strconv.Atoi(rec.StatusCode)
+				size, _ := strconv.Atoi(rec.Length)
+				if !m.allows(rec.URL, statusCode, size, rec.MimeType) {
+					continue
+				}
+
+				if config.UniqueURLs {
+					if seen[rec.URL] {
+						continue
+					}
+					seen[rec.URL] = true
+				}
+
+				all = append(all, rec)
+				total++
+				if config.MaxResults > 0 && total >= config.MaxResults {
+					break outer
+				}
+			}
+		}
+	}
+
+	var writer io.Writer = os.Stdout
+	if config.OutputFile != "" {
+		file, err := os.Create(config.OutputFile)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	if err := writeMultiSourceResults(all, config, writer); err != nil {
+		return err
+	}
+
+	logr.Info("finished multi-source crawl", map[string]interface{}{
+		"url": inputURL, "sources": sourceNames(sources), "results": len(all), "cancelled": cancelled,
+	})
+
+	if cancelled {
+		return errDeadlineExceeded
+	}
+	return nil
+}